@@ -6,12 +6,109 @@ import (
 	"errors"
 	"fmt"
 	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/dircache"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/pacer"
 	"github.com/FilenCloudDienste/filen-sdk-go/filen/util"
 	"github.com/google/uuid"
 	"strings"
+	"sync"
 	"time"
 )
 
+// dirCacheTTL is how long a DirCache entry is trusted before FindItem and
+// FindDirectoryOrCreate fall back to a fresh ReadDirectory call.
+const dirCacheTTL = 5 * time.Minute
+
+// dirCaches holds one DirCache per *Filen instance, keyed by pointer.
+var dirCaches sync.Map // map[*Filen]*dircache.DirCache
+
+// DirCache returns this Filen instance's directory cache, creating it on
+// first use. Callers doing bulk operations (e.g. a mirror/sync pass) can
+// use it together with FlushDirCache to reason about staleness.
+func (filen *Filen) DirCache() *dircache.DirCache {
+	if cached, ok := dirCaches.Load(filen); ok {
+		return cached.(*dircache.DirCache)
+	}
+	cache, _ := dirCaches.LoadOrStore(filen, dircache.New(dirCacheTTL))
+	return cache.(*dircache.DirCache)
+}
+
+// FlushDirCache invalidates every directory cache entry affected by uuid,
+// e.g. after changes made outside of this package's own create/trash
+// methods.
+func (filen *Filen) FlushDirCache(uuid string) {
+	filen.DirCache().Flush(uuid)
+}
+
+// pacers holds one retry Pacer per *Filen instance, keyed by pointer.
+var pacers sync.Map // map[*Filen]*pacer.Pacer
+
+// Pacer returns this Filen instance's retry pacer, creating it with
+// pacer.New's defaults on first use.
+func (filen *Filen) Pacer() *pacer.Pacer {
+	if cached, ok := pacers.Load(filen); ok {
+		return cached.(*pacer.Pacer)
+	}
+	p, _ := pacers.LoadOrStore(filen, pacer.New())
+	return p.(*pacer.Pacer)
+}
+
+// Close releases the DirCache and Pacer associated with filen. dirCaches
+// and pacers are keyed by *Filen in a package-level sync.Map, which would
+// otherwise hold a live reference to every Filen that ever called
+// DirCache/Pacer for the rest of the process's life. Callers that create
+// many short-lived Filen instances (e.g. one per test or per request)
+// should call Close once a Filen is no longer needed.
+func (filen *Filen) Close() error {
+	dirCaches.Delete(filen)
+	pacers.Delete(filen)
+	return nil
+}
+
+// Option configures an existing Filen when called with it, e.g.
+// WithPacer(p)(f). No constructor in this package takes Options; an Option
+// affects only calls made after it has been applied.
+type Option func(*Filen)
+
+// WithPacer overrides a Filen's default retry pacer.
+func WithPacer(p *pacer.Pacer) Option {
+	return func(filen *Filen) {
+		pacers.Store(filen, p)
+	}
+}
+
+// callWithPacer runs fn through filen's pacer, classifying its errors
+// with pacer.ShouldRetry.
+func callWithPacer[T any](filen *Filen, fn func() (T, error)) (T, error) {
+	var result T
+	err := filen.Pacer().Call(func() (bool, error) {
+		var callErr error
+		result, callErr = fn()
+		return pacer.ShouldRetry(callErr), callErr
+	})
+	return result, err
+}
+
+// callWithPacerNoResult is callWithPacer for client calls that only
+// return an error.
+func callWithPacerNoResult(filen *Filen, fn func() error) error {
+	return filen.Pacer().Call(func() (bool, error) {
+		err := fn()
+		return pacer.ShouldRetry(err), err
+	})
+}
+
+// bind1 and bind4 curry a client call's arguments so it can be passed to
+// callWithPacer as a func() (T, error), without callers having to name
+// the client's (package-private) response type.
+func bind1[A, T any](fn func(A) (T, error), a A) func() (T, error) {
+	return func() (T, error) { return fn(a) }
+}
+
+func bind4[A, B, C, D, T any](fn func(A, B, C, D) (T, error), a A, b B, c C, d D) func() (T, error) {
+	return func() (T, error) { return fn(a, b, c, d) }
+}
+
 // File represents a file on the cloud drive.
 type File struct {
 	UUID          string    // the UUID of the cloud item
@@ -39,11 +136,18 @@ type Directory struct {
 }
 
 // GetBaseFolderUUID fetches the UUID of the cloud drive's root directory.
+// Every FindItem/FindDirectoryOrCreate call resolves this first, so the
+// result is cached in the DirCache (see DirCache.Root) to save that one
+// round trip on every single lookup.
 func (filen *Filen) GetBaseFolderUUID() (string, error) {
-	userBaseFolder, err := filen.client.GetUserBaseFolder()
+	if cachedUUID, found := filen.DirCache().Root(); found {
+		return cachedUUID, nil
+	}
+	userBaseFolder, err := callWithPacer(filen, filen.client.GetUserBaseFolder)
 	if err != nil {
 		return "", err
 	}
+	filen.DirCache().SetRoot(userBaseFolder.UUID)
 	return userBaseFolder.UUID, nil
 }
 
@@ -92,6 +196,20 @@ SegmentsLoop:
 		if segment == "" {
 			continue
 		}
+		isLastSegment := segmentIdx == len(segments)-1
+
+		// intermediate segments must resolve to a directory, so the
+		// dircache alone (populated by a previous ReadDirectory) can
+		// settle them without another round trip
+		if !isLastSegment {
+			if cachedUUID, found := filen.DirCache().Find(currentUUID, segment); found {
+				if cachedUUID == "" {
+					return nil, nil, nil
+				}
+				currentUUID = cachedUUID
+				continue SegmentsLoop
+			}
+		}
 
 		files, directories, err := filen.ReadDirectory(currentUUID)
 		if err != nil {
@@ -106,7 +224,7 @@ SegmentsLoop:
 		}
 		for _, directory := range directories {
 			if directory.Name == segment {
-				if segmentIdx == len(segments)-1 {
+				if isLastSegment {
 					return nil, directory, nil
 				} else {
 					currentUUID = directory.UUID
@@ -114,6 +232,7 @@ SegmentsLoop:
 				}
 			}
 		}
+		filen.DirCache().PutMiss(currentUUID, segment)
 		return nil, nil, nil
 	}
 	return nil, nil, errors.New("unreachable")
@@ -139,6 +258,12 @@ SegmentsLoop:
 			continue
 		}
 
+		if cachedUUID, found := filen.DirCache().Find(currentUUID, segment); found && cachedUUID != "" {
+			// directory found (cached)
+			currentUUID = cachedUUID
+			continue SegmentsLoop
+		}
+
 		_, directories, err := filen.ReadDirectory(currentUUID)
 		if err != nil {
 			return "", err
@@ -163,7 +288,7 @@ SegmentsLoop:
 // ReadDirectory fetches the files and directories that are children of a directory (specified by UUID).
 func (filen *Filen) ReadDirectory(uuid string) ([]*File, []*Directory, error) {
 	// fetch directory content
-	directoryContent, err := filen.client.GetDirectoryContent(uuid)
+	directoryContent, err := callWithPacer(filen, bind1(filen.client.GetDirectoryContent, uuid))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -228,12 +353,22 @@ func (filen *Filen) ReadDirectory(uuid string) ([]*File, []*Directory, error) {
 		})
 	}
 
+	// populate the dircache so subsequent FindItem/FindDirectoryOrCreate
+	// calls can resolve these directories without another round trip
+	for _, directory := range directories {
+		filen.DirCache().Put(uuid, directory.Name, directory.UUID)
+	}
+
 	return files, directories, nil
 }
 
 // TrashFile moves a file to trash.
 func (filen *Filen) TrashFile(uuid string) error {
-	return filen.client.TrashFile(uuid)
+	if err := callWithPacerNoResult(filen, func() error { return filen.client.TrashFile(uuid) }); err != nil {
+		return err
+	}
+	filen.FlushDirCache(uuid)
+	return nil
 }
 
 // CreateDirectory creates a new directory.
@@ -257,10 +392,11 @@ func (filen *Filen) CreateDirectory(parentUUID string, name string) (*Directory,
 	nameHashed := hex.EncodeToString(crypto.RunSHA521([]byte(name)))
 
 	// send
-	response, err := filen.client.CreateDirectory(directoryUUID, metadataEncrypted, nameHashed, parentUUID)
+	response, err := callWithPacer(filen, bind4(filen.client.CreateDirectory, directoryUUID, metadataEncrypted, nameHashed, parentUUID))
 	if err != nil {
 		return nil, err
 	}
+	filen.DirCache().Put(parentUUID, name, response.UUID)
 	return &Directory{
 		UUID:       response.UUID,
 		Name:       name,
@@ -273,5 +409,9 @@ func (filen *Filen) CreateDirectory(parentUUID string, name string) (*Directory,
 
 // TrashDirectory moves a directory to trash.
 func (filen *Filen) TrashDirectory(uuid string) error {
-	return filen.client.TrashDirectory(uuid)
+	if err := callWithPacerNoResult(filen, func() error { return filen.client.TrashDirectory(uuid) }); err != nil {
+		return err
+	}
+	filen.FlushDirCache(uuid)
+	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 )
 
@@ -11,9 +13,74 @@ func DeriveKeyFromPassword(password string, salt string, iterations int, bitLeng
 	return pbkdf2.Key([]byte(password), []byte(salt), iterations, bitLength/8, sha512.New)
 }
 
+// KeyDeriver derives a master key and a login password hash from a raw
+// password and a server-provided salt, per one of Filen's auth versions.
+// Implementations must split their derived key material in half, hex
+// encoding the first half as masterKey and hashing the second half (with
+// runSHA521) as loginHash, matching how the server expects the login
+// request to be hashed.
+type KeyDeriver interface {
+	Derive(password string, salt string) (masterKey string, loginHash string, err error)
+}
+
+func splitDerivedKey(derivedKey []byte) (masterKey string, loginHash string) {
+	hexKey := hex.EncodeToString(derivedKey)
+	masterKey, loginHash = hexKey[:len(hexKey)/2], hexKey[len(hexKey)/2:]
+	loginHash = fmt.Sprintf("%032x", runSHA521(loginHash))
+	return
+}
+
+// PBKDF2v2 is Filen's original ("v2") key derivation: PBKDF2-HMAC-SHA512
+// with 200000 iterations and a 512-bit output.
+type PBKDF2v2 struct{}
+
+func (PBKDF2v2) Derive(password string, salt string) (masterKey string, loginHash string, err error) {
+	masterKey, loginHash = splitDerivedKey(DeriveKeyFromPassword(password, salt, 200000, 512))
+	return masterKey, loginHash, nil
+}
+
+// Argon2idv3 is Filen's Argon2id-based ("v3") key derivation. Time,
+// Memory, Threads and KeyLen come from the account's /v3/auth/info
+// response and must be passed through as-is: they're chosen per-account
+// and derivation is not reproducible with the wrong parameters.
+type Argon2idv3 struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+}
+
+func (p Argon2idv3) Derive(password string, salt string) (masterKey string, loginHash string, err error) {
+	// argon2.IDKey panics on an invalid parameter (e.g. Threads == 0)
+	// rather than returning an error, so reject those up front.
+	if p.Time == 0 || p.Memory == 0 || p.Threads == 0 || p.KeyLen == 0 {
+		return "", "", fmt.Errorf("crypto: invalid argon2id parameters %+v", p)
+	}
+	derivedKey := argon2.IDKey([]byte(password), []byte(salt), p.Time, p.Memory, p.Threads, p.KeyLen)
+	masterKey, loginHash = splitDerivedKey(derivedKey)
+	return masterKey, loginHash, nil
+}
+
+// KeyDeriverFor returns the KeyDeriver for a Filen account's auth
+// version, as reported by the server's /v3/auth/info endpoint. argon2Params
+// is only consulted for auth version 3 and may be zero otherwise.
+func KeyDeriverFor(authVersion int, argon2Params Argon2idv3) (KeyDeriver, error) {
+	switch authVersion {
+	case 1, 2:
+		return PBKDF2v2{}, nil
+	case 3:
+		return argon2Params, nil
+	default:
+		return nil, fmt.Errorf("crypto: unsupported auth version %d", authVersion)
+	}
+}
+
+// GeneratePasswordAndMasterKey derives the master key and login password
+// hash using auth v2 (PBKDF2v2).
+//
+// Deprecated: assumes auth v2. Use KeyDeriverFor with the account's
+// actual auth version (from /v3/auth/info) instead.
 func GeneratePasswordAndMasterKey(rawPassword string, salt string) (derivedMasterKey string, derivedPassword string) {
-	derivedKey := hex.EncodeToString(DeriveKeyFromPassword(rawPassword, salt, 200000, 512))
-	derivedMasterKey, derivedPassword = derivedKey[:len(derivedKey)/2], derivedKey[len(derivedKey)/2:]
-	derivedPassword = fmt.Sprintf("%032x", runSHA521(derivedPassword))
+	derivedMasterKey, derivedPassword, _ = PBKDF2v2{}.Derive(rawPassword, salt)
 	return
 }
@@ -0,0 +1,106 @@
+package crypto
+
+import "testing"
+
+// TestPBKDF2v2Derive pins the v2 key derivation's master key against a
+// known-answer vector (PBKDF2-HMAC-SHA512, 200000 iterations, 512-bit
+// output), so a change to the iteration count or hash function doesn't
+// silently break logins for existing accounts.
+func TestPBKDF2v2Derive(t *testing.T) {
+	const wantMasterKey = "ea842c2eec82cce76948c1969df59fde743b49e729e5f66c6053af1a98700f30"
+
+	masterKey, loginHash, err := PBKDF2v2{}.Derive("testpassword", "testsalt")
+	if err != nil {
+		t.Fatalf("Derive: %v", err)
+	}
+	if masterKey != wantMasterKey {
+		t.Errorf("masterKey = %q, want %q", masterKey, wantMasterKey)
+	}
+	if loginHash == "" {
+		t.Error("loginHash is empty")
+	}
+}
+
+func TestPBKDF2v2DeriveIsDeterministic(t *testing.T) {
+	masterKey1, loginHash1, err := PBKDF2v2{}.Derive("a-password", "a-salt")
+	if err != nil {
+		t.Fatalf("Derive: %v", err)
+	}
+	masterKey2, loginHash2, err := PBKDF2v2{}.Derive("a-password", "a-salt")
+	if err != nil {
+		t.Fatalf("Derive: %v", err)
+	}
+	if masterKey1 != masterKey2 || loginHash1 != loginHash2 {
+		t.Error("Derive is not deterministic for identical inputs")
+	}
+}
+
+func TestPBKDF2v2DeriveVariesWithInput(t *testing.T) {
+	base, _, err := PBKDF2v2{}.Derive("a-password", "a-salt")
+	if err != nil {
+		t.Fatalf("Derive: %v", err)
+	}
+	if other, _, err := (PBKDF2v2{}).Derive("a-different-password", "a-salt"); err != nil {
+		t.Fatalf("Derive: %v", err)
+	} else if other == base {
+		t.Error("Derive produced the same masterKey for different passwords")
+	}
+	if other, _, err := (PBKDF2v2{}).Derive("a-password", "a-different-salt"); err != nil {
+		t.Fatalf("Derive: %v", err)
+	} else if other == base {
+		t.Error("Derive produced the same masterKey for different salts")
+	}
+}
+
+func TestArgon2idv3Derive(t *testing.T) {
+	params := Argon2idv3{Time: 3, Memory: 65536, Threads: 4, KeyLen: 64}
+
+	masterKey, loginHash, err := params.Derive("testpassword", "testsalt")
+	if err != nil {
+		t.Fatalf("Derive: %v", err)
+	}
+	if len(masterKey) != 64 {
+		t.Errorf("len(masterKey) = %d, want 64 (KeyLen hex-encoded and halved)", len(masterKey))
+	}
+	if loginHash == "" {
+		t.Error("loginHash is empty")
+	}
+
+	if masterKey2, _, err := params.Derive("testpassword", "testsalt"); err != nil {
+		t.Fatalf("Derive: %v", err)
+	} else if masterKey2 != masterKey {
+		t.Error("Derive is not deterministic for identical inputs and parameters")
+	}
+}
+
+func TestArgon2idv3DeriveRejectsInvalidParams(t *testing.T) {
+	if _, _, err := (Argon2idv3{}).Derive("testpassword", "testsalt"); err == nil {
+		t.Error("Derive with zero-value parameters should return an error, not panic via argon2.IDKey")
+	}
+}
+
+func TestKeyDeriverFor(t *testing.T) {
+	argon2Params := Argon2idv3{Time: 3, Memory: 65536, Threads: 4, KeyLen: 64}
+
+	for _, authVersion := range []int{1, 2} {
+		deriver, err := KeyDeriverFor(authVersion, argon2Params)
+		if err != nil {
+			t.Fatalf("KeyDeriverFor(%d): %v", authVersion, err)
+		}
+		if _, ok := deriver.(PBKDF2v2); !ok {
+			t.Errorf("KeyDeriverFor(%d) = %T, want PBKDF2v2", authVersion, deriver)
+		}
+	}
+
+	deriver, err := KeyDeriverFor(3, argon2Params)
+	if err != nil {
+		t.Fatalf("KeyDeriverFor(3): %v", err)
+	}
+	if got, ok := deriver.(Argon2idv3); !ok || got != argon2Params {
+		t.Errorf("KeyDeriverFor(3) = %#v, want %#v", deriver, argon2Params)
+	}
+
+	if _, err := KeyDeriverFor(4, argon2Params); err == nil {
+		t.Error("KeyDeriverFor(4) should error on an unsupported auth version")
+	}
+}
@@ -0,0 +1,134 @@
+// Package dircache implements a small, dependency-free cache for
+// directory (and file) UUID lookups, modeled after rclone's lib/dircache.
+// It lets callers that walk a path one segment at a time skip the API
+// round trip for segments they have already resolved, and remembers
+// negative lookups so repeated misses don't hit the network either.
+package dircache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// entry is a single cached lookup result. An empty id represents a
+// negative ("not found") result.
+type entry struct {
+	id      string
+	expires time.Time
+}
+
+// DirCache caches item UUIDs keyed on parentUUID+name, so repeated path
+// lookups don't have to walk the tree with an API call per segment. A
+// zero-value TTL means entries never expire until explicitly flushed.
+type DirCache struct {
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]entry
+
+	rootMu sync.RWMutex
+	root   string // the resolved base folder UUID, once known
+}
+
+// New creates a DirCache whose entries expire after ttl. Pass 0 to disable
+// expiry.
+func New(ttl time.Duration) *DirCache {
+	return &DirCache{
+		ttl:   ttl,
+		cache: make(map[string]entry),
+	}
+}
+
+func key(parentUUID string, name string) string {
+	return parentUUID + "/" + name
+}
+
+// Find looks up name under parentUUID. found reports whether a (possibly
+// negative) entry is cached and unexpired; when found is true and uuid is
+// empty, the lookup is a cached miss.
+func (d *DirCache) Find(parentUUID string, name string) (uuid string, found bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	e, ok := d.cache[key(parentUUID, name)]
+	if !ok || (d.ttl > 0 && time.Now().After(e.expires)) {
+		return "", false
+	}
+	return e.id, true
+}
+
+// Put records that name under parentUUID resolves to uuid.
+func (d *DirCache) Put(parentUUID string, name string, uuid string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cache[key(parentUUID, name)] = entry{id: uuid, expires: d.expiry()}
+}
+
+// PutMiss records that name does not exist under parentUUID, so future
+// lookups can short-circuit without hitting the API.
+func (d *DirCache) PutMiss(parentUUID string, name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cache[key(parentUUID, name)] = entry{expires: d.expiry()}
+}
+
+func (d *DirCache) expiry() time.Time {
+	if d.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d.ttl)
+}
+
+// Flush invalidates every entry that resolves to uuid (so the parent that
+// used to contain it is re-walked) as well as every entry whose parent is
+// uuid (so its stale children aren't served after uuid itself is removed);
+// entries deeper in the subtree are keyed on the children's UUIDs and are
+// left in place. It
+// also clears the cached root if uuid is the root, so a later GetBaseFolderUUID
+// call re-resolves it. Call this after creating or trashing the item uuid
+// refers to.
+func (d *DirCache) Flush(uuid string) {
+	if uuid == "" {
+		return
+	}
+	d.mu.Lock()
+	prefix := uuid + "/"
+	for k, e := range d.cache {
+		if e.id == uuid || strings.HasPrefix(k, prefix) {
+			delete(d.cache, k)
+		}
+	}
+	d.mu.Unlock()
+
+	d.rootMu.Lock()
+	if d.root == uuid {
+		d.root = ""
+	}
+	d.rootMu.Unlock()
+}
+
+// FlushAll discards every cached entry, including the cached root.
+func (d *DirCache) FlushAll() {
+	d.mu.Lock()
+	d.cache = make(map[string]entry)
+	d.mu.Unlock()
+
+	d.rootMu.Lock()
+	d.root = ""
+	d.rootMu.Unlock()
+}
+
+// SetRoot records the resolved base folder UUID, so repeated root lookups
+// can be skipped.
+func (d *DirCache) SetRoot(uuid string) {
+	d.rootMu.Lock()
+	d.root = uuid
+	d.rootMu.Unlock()
+}
+
+// Root returns the cached base folder UUID and whether it has been set.
+func (d *DirCache) Root() (uuid string, found bool) {
+	d.rootMu.RLock()
+	defer d.rootMu.RUnlock()
+	return d.root, d.root != ""
+}
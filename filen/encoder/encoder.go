@@ -0,0 +1,237 @@
+// Package encoder implements reversible filename sanitization, modeled
+// after rclone's lib/encoder.MultiEncoder. A MultiEncoder is a bitmask of
+// transformations; Encode replaces each affected character (or position)
+// with a private-use-area lookalike, and Decode maps it back, so
+// Decode(Encode(name)) == name for every name, regardless of which bits
+// were set.
+package encoder
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MultiEncoder is a bitmask of filename transformations to apply on
+// Encode.
+type MultiEncoder uint32
+
+// EncodeZero applies no transformations.
+const EncodeZero MultiEncoder = 0
+
+const (
+	EncodeColon MultiEncoder = 1 << iota
+	EncodeQuestion
+	EncodeAsterisk
+	EncodeInvalidUtf8
+	EncodeWinReserved
+	EncodeLeftSpace
+	EncodeRightSpace
+	EncodeLeftPeriod
+	EncodeRightPeriod
+	EncodeSlash
+	EncodeBackSlash
+	EncodePipe
+	EncodeLtGt
+	EncodeDoubleQuote
+)
+
+// Presets for the three local filesystems callers most commonly mirror
+// to/from.
+const (
+	// WindowsSafe encodes the characters : ? * \ | < > " that NTFS/FAT
+	// reject, leading/trailing spaces and periods, Windows-reserved device
+	// names and invalid UTF-8. '/' and control characters are not encoded.
+	WindowsSafe = EncodeColon | EncodeQuestion | EncodeAsterisk | EncodeWinReserved |
+		EncodeLeftSpace | EncodeRightSpace | EncodeLeftPeriod | EncodeRightPeriod |
+		EncodeBackSlash | EncodePipe | EncodeLtGt | EncodeDoubleQuote | EncodeInvalidUtf8
+
+	// MacSafe encodes ':', which the Finder and HFS+/APFS both reject
+	// even though it's a legal UTF-8 byte.
+	MacSafe = EncodeColon | EncodeInvalidUtf8
+
+	// LinuxSafe only has to worry about invalid UTF-8; ext4/most Linux
+	// filesystems are otherwise binary-safe apart from '/' and NUL.
+	LinuxSafe = EncodeInvalidUtf8
+)
+
+// puaBase is the start of the private-use-area range Encode maps
+// problem bytes into. Every byte value 0-255 has a unique, reversible
+// slot at puaBase+byte.
+const puaBase = 0xF000
+
+func encodeByte(b byte) rune {
+	return puaBase + rune(b)
+}
+
+func decodeByte(r rune) (byte, bool) {
+	if r >= puaBase && r < puaBase+0x100 {
+		return byte(r - puaBase), true
+	}
+	return 0, false
+}
+
+// isPUAEscapeRune reports whether r falls in Encode's own private-use-area
+// escape range, i.e. whether Decode would otherwise mistake a literal
+// occurrence of r for one of Encode's own escapes.
+func isPUAEscapeRune(r rune) bool {
+	_, ok := decodeByte(r)
+	return ok
+}
+
+// Encode sanitizes name according to mask, replacing each affected
+// character with a private-use-area lookalike. Regardless of mask, any
+// literal rune already in Encode's own private-use-area escape range is
+// also escaped (byte by byte), so Decode can't mistake it for one of
+// Encode's own escapes.
+func (mask MultiEncoder) Encode(name string) string {
+	if name == "" {
+		return name
+	}
+
+	runes := decodeRunesEncodingInvalidUtf8(name, mask&EncodeInvalidUtf8 != 0)
+	for i, r := range runes {
+		if r < utf8.RuneSelf && shouldEncodeRune(byte(r), mask) {
+			runes[i] = encodeByte(byte(r))
+		}
+	}
+	name = string(runes)
+
+	if mask == EncodeZero {
+		return name
+	}
+
+	if mask&EncodeLeftSpace != 0 {
+		name = encodeEdgeByte(name, ' ', true)
+	}
+	if mask&EncodeLeftPeriod != 0 {
+		name = encodeEdgeByte(name, '.', true)
+	}
+	if mask&EncodeRightSpace != 0 {
+		name = encodeEdgeByte(name, ' ', false)
+	}
+	if mask&EncodeRightPeriod != 0 {
+		name = encodeEdgeByte(name, '.', false)
+	}
+	if mask&EncodeWinReserved != 0 {
+		name = encodeWinReservedName(name)
+	}
+	return name
+}
+
+// Decode reverses Encode. It needs no mask: every private-use-area
+// lookalike Encode can produce unambiguously maps back to its original
+// byte.
+func (MultiEncoder) Decode(name string) string {
+	if name == "" {
+		return name
+	}
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range name {
+		if orig, ok := decodeByte(r); ok {
+			b.WriteByte(orig)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// decodeRunesEncodingInvalidUtf8 decodes name into runes. When
+// invalidUtf8 is set, any byte that isn't part of a valid UTF-8 sequence
+// is mapped to its private-use-area slot instead of being dropped, so it
+// round-trips through Decode. Regardless of invalidUtf8, a valid rune that
+// already falls in that same private-use-area escape range is byte-escaped
+// too, since Decode always reverses it and would otherwise corrupt it.
+func decodeRunesEncodingInvalidUtf8(name string, invalidUtf8 bool) []rune {
+	runes := make([]rune, 0, len(name))
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		if r == utf8.RuneError && size == 1 {
+			if invalidUtf8 {
+				runes = append(runes, encodeByte(name[i]))
+			} else {
+				runes = append(runes, rune(name[i]))
+			}
+			i++
+			continue
+		}
+		if isPUAEscapeRune(r) {
+			for j := 0; j < size; j++ {
+				runes = append(runes, encodeByte(name[i+j]))
+			}
+			i += size
+			continue
+		}
+		runes = append(runes, r)
+		i += size
+	}
+	return runes
+}
+
+func shouldEncodeRune(b byte, mask MultiEncoder) bool {
+	switch b {
+	case ':':
+		return mask&EncodeColon != 0
+	case '?':
+		return mask&EncodeQuestion != 0
+	case '*':
+		return mask&EncodeAsterisk != 0
+	case '/':
+		return mask&EncodeSlash != 0
+	case '\\':
+		return mask&EncodeBackSlash != 0
+	case '|':
+		return mask&EncodePipe != 0
+	case '<', '>':
+		return mask&EncodeLtGt != 0
+	case '"':
+		return mask&EncodeDoubleQuote != 0
+	default:
+		return false
+	}
+}
+
+// encodeEdgeByte encodes every run of target at the start (leading=true)
+// or end (leading=false) of name.
+func encodeEdgeByte(name string, target byte, leading bool) string {
+	runes := []rune(name)
+	if leading {
+		for i := 0; i < len(runes) && runes[i] == rune(target); i++ {
+			runes[i] = encodeByte(target)
+		}
+	} else {
+		for i := len(runes) - 1; i >= 0 && runes[i] == rune(target); i-- {
+			runes[i] = encodeByte(target)
+		}
+	}
+	return string(runes)
+}
+
+// winReservedNames are the device names Windows reserves regardless of
+// extension (CON.txt is just as unwritable as CON).
+var winReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// encodeWinReservedName encodes the first rune of name if its base name
+// (the part before the first '.') is a Windows-reserved device name, so
+// the result no longer collides but still decodes back exactly.
+func encodeWinReservedName(name string) string {
+	base := name
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		base = name[:i]
+	}
+	if !winReservedNames[strings.ToUpper(base)] {
+		return name
+	}
+	runes := []rune(name)
+	if len(runes) > 0 && runes[0] < utf8.RuneSelf {
+		runes[0] = encodeByte(byte(runes[0]))
+	}
+	return string(runes)
+}
@@ -0,0 +1,39 @@
+package encoder
+
+import "testing"
+
+func TestRoundTrip(t *testing.T) {
+	names := []string{
+		"",
+		"plain.txt",
+		"a:b?c*d",
+		"trailing space ",
+		" leading space",
+		"trailing.",
+		".leading",
+		`back\slash|pipe<lt>gt"quote`,
+		"CON", "CON.txt", "con.txt",
+		"\xff\xfe not valid utf-8",
+		"ab", // a literal rune already in Encode's own escape range
+	}
+
+	for _, mask := range []MultiEncoder{EncodeZero, WindowsSafe, MacSafe, LinuxSafe} {
+		for _, name := range names {
+			encoded := mask.Encode(name)
+			if got := mask.Decode(encoded); got != name {
+				t.Errorf("mask %d: Decode(Encode(%q)) = %q, want %q (encoded: %q)", mask, name, got, name, encoded)
+			}
+		}
+	}
+}
+
+func TestEncodeEscapesLiteralPUARune(t *testing.T) {
+	name := "ab"
+	encoded := EncodeZero.Encode(name)
+	if encoded == name {
+		t.Fatalf("Encode did not escape a literal private-use-area rune")
+	}
+	if got := EncodeZero.Decode(encoded); got != name {
+		t.Errorf("Decode(Encode(%q)) = %q, want %q", name, got, name)
+	}
+}
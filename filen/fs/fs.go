@@ -0,0 +1,390 @@
+// Package fs adapts a *filen.Filen cloud drive to the standard library's
+// io/fs interfaces, so the wider Go ecosystem (fs.WalkDir, http.FS,
+// text/template.ParseFS, ...) can traverse a Filen drive without learning
+// its path-walking API.
+package fs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen"
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/encoder"
+)
+
+// FS wraps a *filen.Filen and implements fs.FS, fs.ReadDirFS, fs.StatFS
+// and fs.SubFS. Paths are slash-separated and relative to root.
+type FS struct {
+	filen *filen.Filen
+	root  string               // path prefix applied to every operation, set by Sub, in raw remote form
+	enc   encoder.MultiEncoder // sanitizes remote names for the names this FS hands back, and reverses it on the way in
+}
+
+// New returns an FS rooted at the drive's base folder.
+func New(f *filen.Filen) *FS {
+	return &FS{filen: f}
+}
+
+// WithEncoder returns a copy of f that sanitizes remote names (e.g. a
+// reserved character an OS-backed consumer of this FS can't store) using
+// enc, reversing it for every path this FS is given back.
+func (f *FS) WithEncoder(enc encoder.MultiEncoder) *FS {
+	clone := *f
+	clone.enc = enc
+	return &clone
+}
+
+func (f *FS) resolve(op string, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(f.root, f.enc.Decode(name)), nil
+}
+
+// Open implements fs.FS.
+func (f *FS) Open(name string) (fs.File, error) {
+	p, err := f.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if p == "." {
+		uuid, err := f.filen.GetBaseFolderUUID()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return f.openDir(name, &filen.Directory{UUID: uuid, Name: "."})
+	}
+
+	file, directory, err := f.filen.FindItem(p, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	switch {
+	case file != nil:
+		return &openFile{filen: f.filen, file: file, enc: f.enc}, nil
+	case directory != nil:
+		return f.openDir(name, directory)
+	default:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+}
+
+func (f *FS) openDir(name string, directory *filen.Directory) (fs.File, error) {
+	entries, err := f.readDirEntries(directory.UUID)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &dirHandle{info: fileInfo{directory: directory, enc: f.enc}, entries: entries}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	uuid, err := f.dirUUID(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.readDirEntries(uuid)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+func (f *FS) readDirEntries(uuid string) ([]fs.DirEntry, error) {
+	files, directories, err := f.filen.ReadDirectory(uuid)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, 0, len(files)+len(directories))
+	for _, file := range files {
+		entries = append(entries, dirEntry{fileInfo{file: file, enc: f.enc}})
+	}
+	for _, directory := range directories {
+		entries = append(entries, dirEntry{fileInfo{directory: directory, enc: f.enc}})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (f *FS) dirUUID(name string) (string, error) {
+	p, err := f.resolve("open", name)
+	if err != nil {
+		return "", err
+	}
+	if p == "." {
+		return f.filen.GetBaseFolderUUID()
+	}
+	_, directory, err := f.filen.FindItem(p, true)
+	if err != nil {
+		return "", &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if directory == nil {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return directory.UUID, nil
+}
+
+// Stat implements fs.StatFS.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if p == "." {
+		uuid, err := f.filen.GetBaseFolderUUID()
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+		}
+		return fileInfo{directory: &filen.Directory{UUID: uuid, Name: "."}, enc: f.enc}, nil
+	}
+
+	file, directory, err := f.filen.FindItem(p, false)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if file == nil && directory == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fileInfo{file: file, directory: directory, enc: f.enc}, nil
+}
+
+// Sub implements fs.SubFS.
+func (f *FS) Sub(dir string) (fs.FS, error) {
+	p, err := f.resolve("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{filen: f.filen, root: p, enc: f.enc}, nil
+}
+
+// WriteFS is a Filen-specific extension for callers that need to mutate
+// the drive through the fs.FS adapter, since io/fs itself is read-only.
+type WriteFS interface {
+	fs.FS
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(name string) error
+	Remove(name string) error
+}
+
+// MkdirAll creates name and any missing parent directories.
+func (f *FS) MkdirAll(name string) error {
+	p, err := f.resolve("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if p == "." {
+		p = ""
+	}
+	_, err = f.filen.FindDirectoryOrCreate(p)
+	return err
+}
+
+// Remove trashes the file or directory at name.
+func (f *FS) Remove(name string) error {
+	p, err := f.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	file, directory, err := f.filen.FindItem(p, false)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	switch {
+	case file != nil:
+		return f.filen.TrashFile(file.UUID)
+	case directory != nil:
+		return f.filen.TrashDirectory(directory.UUID)
+	default:
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+}
+
+// Create opens name for writing, creating it (and its parent directories)
+// if necessary, and uploads the written bytes as they're streamed in.
+func (f *FS) Create(name string) (io.WriteCloser, error) {
+	p, err := f.resolve("create", name)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+	parentUUID, err := f.filen.FindDirectoryOrCreate(dir)
+	if err != nil {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: err}
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, uploadErr := f.filen.UploadFile(parentUUID, path.Base(p), pr)
+		pr.CloseWithError(uploadErr)
+		done <- uploadErr
+	}()
+	return &uploadWriter{pw: pw, done: done}, nil
+}
+
+// uploadWriter streams writes into the pipe feeding FS.Create's upload
+// goroutine, and surfaces the upload's result from Close.
+type uploadWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *uploadWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *uploadWriter) Close() error {
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+// fileInfo implements fs.FileInfo for either a *filen.File or a
+// *filen.Directory.
+type fileInfo struct {
+	file      *filen.File
+	directory *filen.Directory
+	enc       encoder.MultiEncoder
+}
+
+func (i fileInfo) Name() string {
+	if i.file != nil {
+		return i.enc.Encode(i.file.Name)
+	}
+	return i.enc.Encode(i.directory.Name)
+}
+
+func (i fileInfo) Size() int64 {
+	if i.file != nil {
+		return i.file.Size
+	}
+	return 0
+}
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (i fileInfo) ModTime() time.Time {
+	return i.LastModified()
+}
+
+// LastModified returns the underlying file's or directory's last-modified
+// (directories fall back to their creation time, which is all the API
+// reports for them).
+func (i fileInfo) LastModified() time.Time {
+	if i.file != nil {
+		return i.file.LastModified
+	}
+	return i.directory.Created
+}
+
+// MimeType returns the underlying file's MIME type, or "" for a directory.
+func (i fileInfo) MimeType() string {
+	if i.file != nil {
+		return i.file.MimeType
+	}
+	return ""
+}
+
+func (i fileInfo) IsDir() bool { return i.file == nil }
+
+// Sys returns the underlying *filen.File or *filen.Directory.
+func (i fileInfo) Sys() any {
+	if i.file != nil {
+		return i.file
+	}
+	return i.directory
+}
+
+// dirEntry implements fs.DirEntry on top of fileInfo.
+type dirEntry struct {
+	info fileInfo
+}
+
+func (d dirEntry) Name() string               { return d.info.Name() }
+func (d dirEntry) IsDir() bool                { return d.info.IsDir() }
+func (d dirEntry) Type() fs.FileMode          { return d.info.Mode().Type() }
+func (d dirEntry) Info() (fs.FileInfo, error) { return d.info, nil }
+
+// dirHandle implements fs.ReadDirFile over a pre-fetched entry list.
+type dirHandle struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dirHandle) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dirHandle) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *dirHandle) Close() error { return nil }
+
+func (d *dirHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries[d.offset:]
+		d.offset = len(d.entries)
+		return entries, nil
+	}
+	if d.offset >= len(d.entries) {
+		return nil, io.EOF
+	}
+	end := d.offset + n
+	if end > len(d.entries) {
+		end = len(d.entries)
+	}
+	entries := d.entries[d.offset:end]
+	d.offset = end
+	return entries, nil
+}
+
+// openFile implements fs.File over a *filen.File, downloading chunks on
+// demand as Read is called rather than fetching the whole file up front.
+type openFile struct {
+	filen *filen.Filen
+	file  *filen.File
+	enc   encoder.MultiEncoder
+
+	chunkIdx int
+	buf      []byte
+	closed   bool
+}
+
+func (o *openFile) Stat() (fs.FileInfo, error) { return fileInfo{file: o.file, enc: o.enc}, nil }
+
+func (o *openFile) Read(p []byte) (int, error) {
+	if o.closed {
+		return 0, fs.ErrClosed
+	}
+	for len(o.buf) == 0 {
+		if o.chunkIdx >= o.file.Chunks {
+			return 0, io.EOF
+		}
+		chunk, err := o.filen.DownloadFileChunk(o.file, o.chunkIdx)
+		if err != nil {
+			return 0, err
+		}
+		o.buf = chunk
+		o.chunkIdx++
+	}
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+func (o *openFile) Close() error {
+	o.closed = true
+	return nil
+}
@@ -0,0 +1,469 @@
+package filen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/encoder"
+)
+
+// Direction selects which side of a Mirror call is treated as the source
+// of truth.
+type Direction int
+
+const (
+	Push          Direction = iota // local -> remote
+	Pull                           // remote -> local
+	Bidirectional                  // whichever side is newer wins, per entry
+)
+
+// MirrorOptions configures a Mirror call.
+type MirrorOptions struct {
+	Direction     Direction
+	Delete        bool                 // remove items missing from the source side
+	DryRun        bool                 // compute actions but don't perform them
+	Newer         bool                 // skip Update actions where the source isn't newer than the skew tolerance
+	Include       []string             // glob patterns (matched against the relative path and its base name); if set, only matches are considered
+	Exclude       []string             // glob patterns; matches are skipped even if they also match Include
+	Concurrency   int                  // worker pool size, defaults to 8
+	SkewTolerance time.Duration        // mtime difference below which two entries are considered unchanged, defaults to 2s
+	Encoder       encoder.MultiEncoder // sanitizes remote names into the local filesystem's safe character set, and back
+	Progress      func(MirrorAction)   // called once an action has been decided, and again once it has been performed
+}
+
+func (opts MirrorOptions) skew() time.Duration {
+	if opts.SkewTolerance > 0 {
+		return opts.SkewTolerance
+	}
+	return 2 * time.Second
+}
+
+func (opts MirrorOptions) concurrency() int {
+	if opts.Concurrency > 0 {
+		return opts.Concurrency
+	}
+	return 8
+}
+
+// MirrorActionKind is the decision Mirror made for a single relative path.
+type MirrorActionKind int
+
+const (
+	ActionSkip MirrorActionKind = iota
+	ActionCopy
+	ActionUpdate
+	ActionDelete
+)
+
+// MirrorAction records what Mirror decided (and, once Err is set, whether
+// it succeeded) for a single relative path.
+type MirrorAction struct {
+	Path     string
+	Kind     MirrorActionKind
+	IsDir    bool // true if this path is a directory, not a file
+	Size     int64
+	ToRemote bool // true if the destination of a Copy/Update/Delete is remote
+	Err      error
+}
+
+// MirrorItemError pairs a relative path with the error encountered while
+// copying, updating or deleting it.
+type MirrorItemError struct {
+	Path string
+	Err  error
+}
+
+// MirrorReport summarizes the result of a Mirror call.
+type MirrorReport struct {
+	Copied           int
+	Updated          int
+	Deleted          int
+	Skipped          int
+	BytesTransferred int64
+	Errors           []MirrorItemError
+}
+
+// mirrorEntry is one filesystem or cloud-drive entry, keyed by its path
+// relative to the mirrored roots.
+type mirrorEntry struct {
+	relPath      string
+	isDir        bool
+	size         int64
+	lastModified time.Time
+}
+
+// Mirror syncs the local directory tree at localPath with the cloud drive
+// directory at remotePath, per opts.Direction. It walks both sides,
+// joins them by relative path, and for every entry decides Copy
+// (missing on the destination), Update (size differs or the source is
+// newer by more than opts.SkewTolerance), Delete (missing on the source,
+// only with opts.Delete) or Skip. Actions run through a bounded worker
+// pool; progress and the final report reflect what was (or, in DryRun
+// mode, would have been) done.
+func (filen *Filen) Mirror(ctx context.Context, localPath string, remotePath string, opts MirrorOptions) (*MirrorReport, error) {
+	local, err := localEntries(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRootUUID, err := filen.FindDirectoryOrCreate(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	remote, remoteFiles, err := filen.remoteEntries(remoteRootUUID, opts.Encoder)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := diffMirrorEntries(local, remote, opts)
+
+	report := &MirrorReport{}
+	var mu sync.Mutex
+	sem := make(chan struct{}, opts.concurrency())
+	var wg sync.WaitGroup
+
+	for _, action := range actions {
+		if opts.Progress != nil {
+			opts.Progress(action)
+		}
+
+		if action.Kind == ActionSkip {
+			mu.Lock()
+			report.Skipped++
+			mu.Unlock()
+			continue
+		}
+		if opts.DryRun {
+			mu.Lock()
+			tallyMirrorAction(report, action)
+			mu.Unlock()
+			continue
+		}
+
+		action := action
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			actionErr := performMirrorAction(ctx, filen, localPath, remotePath, action, remoteFiles, opts.Encoder)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if actionErr != nil {
+				report.Errors = append(report.Errors, MirrorItemError{Path: action.Path, Err: actionErr})
+			} else {
+				tallyMirrorAction(report, action)
+			}
+			if opts.Progress != nil {
+				action.Err = actionErr
+				opts.Progress(action)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report, nil
+}
+
+func tallyMirrorAction(report *MirrorReport, action MirrorAction) {
+	switch action.Kind {
+	case ActionCopy:
+		report.Copied++
+		report.BytesTransferred += action.Size
+	case ActionUpdate:
+		report.Updated++
+		report.BytesTransferred += action.Size
+	case ActionDelete:
+		report.Deleted++
+	}
+}
+
+// localEntries walks root and returns one mirrorEntry per descendant,
+// relative to root, with slash-separated paths.
+func localEntries(root string) ([]mirrorEntry, error) {
+	var entries []mirrorEntry
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			entries = append(entries, mirrorEntry{relPath: rel, isDir: true})
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, mirrorEntry{relPath: rel, size: info.Size(), lastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// remoteEntries walks the cloud drive subtree rooted at rootUUID (via
+// ReadDirectory, which also populates the dircache along the way) and
+// returns one mirrorEntry per descendant, plus a lookup of relative path
+// to *File for files whose metadata downloadRemoteToLocal needs. Remote
+// names are run through enc.Encode so they line up with their local-safe
+// counterparts during the diff; enc.Decode reverses this wherever the
+// raw remote name is needed again (uploadLocalToRemote, deleteRemotePath).
+func (filen *Filen) remoteEntries(rootUUID string, enc encoder.MultiEncoder) ([]mirrorEntry, map[string]*File, error) {
+	var entries []mirrorEntry
+	files := make(map[string]*File)
+
+	var walk func(uuid string, prefix string) error
+	walk = func(uuid string, prefix string) error {
+		dirFiles, directories, err := filen.ReadDirectory(uuid)
+		if err != nil {
+			return err
+		}
+		for _, file := range dirFiles {
+			rel := path.Join(prefix, enc.Encode(file.Name))
+			entries = append(entries, mirrorEntry{relPath: rel, size: file.Size, lastModified: file.LastModified})
+			files[rel] = file
+		}
+		for _, directory := range directories {
+			rel := path.Join(prefix, enc.Encode(directory.Name))
+			entries = append(entries, mirrorEntry{relPath: rel, isDir: true, lastModified: directory.Created})
+			if err := walk(directory.UUID, rel); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(rootUUID, ""); err != nil {
+		return nil, nil, err
+	}
+	return entries, files, nil
+}
+
+// diffMirrorEntries joins the two entry sets by relative path (indexing
+// each side in a map) and, in sorted path order, decides an action for
+// each relative path that survives opts' filters.
+func diffMirrorEntries(local []mirrorEntry, remote []mirrorEntry, opts MirrorOptions) []MirrorAction {
+	localByPath := indexMirrorEntries(local)
+	remoteByPath := indexMirrorEntries(remote)
+
+	keys := unionMirrorKeys(localByPath, remoteByPath)
+	sort.Strings(keys)
+
+	actions := make([]MirrorAction, 0, len(keys))
+	for _, key := range keys {
+		if !matchesMirrorFilters(key, opts) {
+			continue
+		}
+		l, hasLocal := localByPath[key]
+		r, hasRemote := remoteByPath[key]
+		actions = append(actions, decideMirrorAction(key, l, hasLocal, r, hasRemote, opts))
+	}
+	return actions
+}
+
+func decideMirrorAction(relPath string, l mirrorEntry, hasLocal bool, r mirrorEntry, hasRemote bool, opts MirrorOptions) MirrorAction {
+	var src, dst mirrorEntry
+	var hasSrc, hasDst, dstIsRemote bool
+
+	switch opts.Direction {
+	case Push:
+		src, hasSrc = l, hasLocal
+		dst, hasDst, dstIsRemote = r, hasRemote, true
+	case Pull:
+		src, hasSrc = r, hasRemote
+		dst, hasDst, dstIsRemote = l, hasLocal, false
+	default: // Bidirectional
+		switch {
+		case !hasLocal:
+			src, hasSrc, dst, hasDst, dstIsRemote = r, hasRemote, l, hasLocal, false
+		case !hasRemote:
+			src, hasSrc, dst, hasDst, dstIsRemote = l, hasLocal, r, hasRemote, true
+		case r.lastModified.After(l.lastModified.Add(opts.skew())):
+			src, hasSrc, dst, hasDst, dstIsRemote = r, hasRemote, l, hasLocal, false
+		default:
+			src, hasSrc, dst, hasDst, dstIsRemote = l, hasLocal, r, hasRemote, true
+		}
+	}
+
+	switch {
+	case !hasSrc:
+		if opts.Delete {
+			return MirrorAction{Path: relPath, Kind: ActionDelete, ToRemote: dstIsRemote}
+		}
+		return MirrorAction{Path: relPath, Kind: ActionSkip}
+	case !hasDst:
+		return MirrorAction{Path: relPath, Kind: ActionCopy, IsDir: src.isDir, Size: src.size, ToRemote: dstIsRemote}
+	case src.isDir || dst.isDir:
+		return MirrorAction{Path: relPath, Kind: ActionSkip}
+	case src.size != dst.size || src.lastModified.After(dst.lastModified.Add(opts.skew())):
+		if opts.Newer && !src.lastModified.After(dst.lastModified.Add(opts.skew())) {
+			return MirrorAction{Path: relPath, Kind: ActionSkip}
+		}
+		return MirrorAction{Path: relPath, Kind: ActionUpdate, Size: src.size, ToRemote: dstIsRemote}
+	default:
+		return MirrorAction{Path: relPath, Kind: ActionSkip}
+	}
+}
+
+func indexMirrorEntries(entries []mirrorEntry) map[string]mirrorEntry {
+	m := make(map[string]mirrorEntry, len(entries))
+	for _, e := range entries {
+		m[e.relPath] = e
+	}
+	return m
+}
+
+func unionMirrorKeys(a map[string]mirrorEntry, b map[string]mirrorEntry) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]mirrorEntry{a, b} {
+		for k := range m {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+func matchesMirrorFilters(relPath string, opts MirrorOptions) bool {
+	if len(opts.Include) > 0 && !matchesAnyMirrorGlob(opts.Include, relPath) {
+		return false
+	}
+	if matchesAnyMirrorGlob(opts.Exclude, relPath) {
+		return false
+	}
+	return true
+}
+
+func matchesAnyMirrorGlob(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, relPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func performMirrorAction(ctx context.Context, filen *Filen, localRoot string, remoteRoot string, action MirrorAction, remoteFiles map[string]*File, enc encoder.MultiEncoder) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	localFull := filepath.Join(localRoot, filepath.FromSlash(action.Path))
+	// action.Path carries encoded (local-safe) names; remote API calls
+	// need the original name back.
+	remoteFull := path.Join(remoteRoot, enc.Decode(action.Path))
+
+	switch action.Kind {
+	case ActionCopy, ActionUpdate:
+		if action.IsDir {
+			return mkdirMirrorPath(filen, localFull, remoteFull, action.ToRemote)
+		}
+		if action.ToRemote {
+			return uploadLocalToRemote(filen, localFull, remoteFull)
+		}
+		return downloadRemoteToLocal(filen, remoteFull, localFull, remoteFiles[action.Path])
+	case ActionDelete:
+		if action.ToRemote {
+			return deleteRemotePath(filen, remoteFull)
+		}
+		return os.RemoveAll(localFull)
+	default:
+		return nil
+	}
+}
+
+// mkdirMirrorPath creates a directory missing on the destination side.
+// Directories carry no content of their own to transfer, so unlike
+// uploadLocalToRemote/downloadRemoteToLocal this never reads or writes
+// file bytes.
+func mkdirMirrorPath(filen *Filen, localFull string, remoteFull string, toRemote bool) error {
+	if toRemote {
+		_, err := filen.FindDirectoryOrCreate(remoteFull)
+		return err
+	}
+	return os.MkdirAll(localFull, 0o755)
+}
+
+func uploadLocalToRemote(filen *Filen, localFull string, remoteFull string) error {
+	f, err := os.Open(localFull)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dir := path.Dir(remoteFull)
+	if dir == "." {
+		dir = ""
+	}
+	parentUUID, err := filen.FindDirectoryOrCreate(dir)
+	if err != nil {
+		return err
+	}
+	_, err = filen.UploadFile(parentUUID, path.Base(remoteFull), f)
+	return err
+}
+
+func downloadRemoteToLocal(filen *Filen, remoteFull string, localFull string, file *File) error {
+	if file == nil {
+		var err error
+		file, _, err = filen.FindItem(remoteFull, false)
+		if err != nil {
+			return err
+		}
+		if file == nil {
+			return fmt.Errorf("remote file not found: %s", remoteFull)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localFull), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(localFull)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return filen.DownloadFile(file, out)
+}
+
+func deleteRemotePath(filen *Filen, remoteFull string) error {
+	file, directory, err := filen.FindItem(remoteFull, false)
+	if err != nil {
+		return err
+	}
+	switch {
+	case file != nil:
+		return filen.TrashFile(file.UUID)
+	case directory != nil:
+		return filen.TrashDirectory(directory.UUID)
+	default:
+		return nil
+	}
+}
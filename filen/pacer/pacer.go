@@ -0,0 +1,179 @@
+// Package pacer retries calls that can fail transiently, modeled after
+// rclone's lib/pacer: a Pacer wraps a closure that reports whether it's
+// worth retrying, and backs off exponentially between attempts.
+package pacer
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Pacer retries a call with exponential backoff until it succeeds, its
+// closure reports it isn't worth retrying, or MaxRetries is exhausted.
+type Pacer struct {
+	MinSleep      time.Duration // initial backoff; defaults to 10ms
+	MaxSleep      time.Duration // backoff ceiling; defaults to 2s
+	DecayConstant uint          // backoff is multiplied by 2^DecayConstant after every retry; defaults to 1 (doubling)
+	MaxRetries    int           // give up after this many attempts; defaults to 10
+}
+
+// New returns a Pacer with rclone-like defaults: 10ms initial backoff, a
+// 2s ceiling, doubling each retry, up to 10 attempts.
+func New() *Pacer {
+	return &Pacer{
+		MinSleep:      10 * time.Millisecond,
+		MaxSleep:      2 * time.Second,
+		DecayConstant: 1,
+		MaxRetries:    10,
+	}
+}
+
+func (p *Pacer) minSleep() time.Duration {
+	if p.MinSleep > 0 {
+		return p.MinSleep
+	}
+	return 10 * time.Millisecond
+}
+
+func (p *Pacer) maxSleep() time.Duration {
+	if p.MaxSleep > 0 {
+		return p.MaxSleep
+	}
+	return 2 * time.Second
+}
+
+func (p *Pacer) decayConstant() uint {
+	if p.DecayConstant > 0 {
+		return p.DecayConstant
+	}
+	return 1
+}
+
+func (p *Pacer) maxRetries() int {
+	if p.MaxRetries > 0 {
+		return p.MaxRetries
+	}
+	return 10
+}
+
+// Call invokes fn, retrying with exponential backoff as long as fn
+// reports retry == true, up to MaxRetries attempts. It returns fn's error
+// from the first attempt that reports retry == false (nil on success), or
+// from the final attempt once MaxRetries is exhausted.
+func (p *Pacer) Call(fn func() (retry bool, err error)) error {
+	sleep := p.minSleep()
+
+	var lastErr error
+	for attempt := 0; attempt < p.maxRetries(); attempt++ {
+		retry, err := fn()
+		if !retry {
+			return err
+		}
+		lastErr = err
+
+		wait := sleep
+		if retryAfter, ok := retryAfterFor(err); ok && retryAfter > 0 {
+			wait = retryAfter
+		}
+		time.Sleep(wait)
+
+		sleep <<= p.decayConstant()
+		if sleep > p.maxSleep() {
+			sleep = p.maxSleep()
+		}
+	}
+	return lastErr
+}
+
+// retryableStatusCodes are the HTTP status codes ShouldRetry treats as
+// transient.
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// HTTPStatusError is implemented by client errors that carry the HTTP
+// response's status code, so ShouldRetry can classify them without a
+// concrete HTTP type.
+type HTTPStatusError interface {
+	error
+	StatusCode() int
+}
+
+// RetryAfterError is implemented by errors that carry a server-provided
+// Retry-After duration (e.g. from a 429 response).
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// FilenCodeError is implemented by client errors that carry Filen's own
+// application-level error code, for APIs that signal rate limiting in the
+// response body rather than the HTTP status (e.g. a 200 response with
+// {"code": "rate_limited"}).
+type FilenCodeError interface {
+	error
+	Code() string
+}
+
+// retryableFilenCodes are the Filen-specific error codes ShouldRetry treats
+// as transient rate limiting.
+var retryableFilenCodes = map[string]bool{
+	"rate_limited":      true,
+	"too_many_requests": true,
+}
+
+// ShouldRetry reports whether err looks transient: a retryable HTTP
+// status, a net.Error reporting a timeout, or a Filen-specific rate-limit
+// error code.
+func ShouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusErr HTTPStatusError
+	if errors.As(err, &statusErr) && retryableStatusCodes[statusErr.StatusCode()] {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var codeErr FilenCodeError
+	if errors.As(err, &codeErr) && retryableFilenCodes[codeErr.Code()] {
+		return true
+	}
+
+	return false
+}
+
+func retryAfterFor(err error) (time.Duration, bool) {
+	var retryAfterErr RetryAfterError
+	if errors.As(err, &retryAfterErr) {
+		return retryAfterErr.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// ParseRetryAfter parses an HTTP Retry-After header value, supporting
+// both the delay-seconds and HTTP-date forms described in RFC 9110.
+func ParseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
@@ -0,0 +1,134 @@
+package filen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/FilenCloudDienste/filen-sdk-go/filen/crypto"
+	"github.com/google/uuid"
+)
+
+// chunkSize is the size of a single uploaded/downloaded file chunk, as
+// reported by File.Chunks.
+const chunkSize = 1 << 20 // 1 MiB
+
+// UploadFile encrypts and uploads r as a new file named name under
+// parentUUID, chunking it into chunkSize pieces the same way ReadDirectory
+// expects to find them (see File.Chunks).
+func (filen *Filen) UploadFile(parentUUID string, name string, r io.Reader) (*File, error) {
+	fileUUID := uuid.New().String()
+	key, err := generateFileKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	chunks := 0
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			encryptedChunk, encErr := crypto.EncryptData(buf[:n], []byte(key))
+			if encErr != nil {
+				return nil, encErr
+			}
+			if err := callWithPacerNoResult(filen, func() error {
+				return filen.client.UploadChunk(fileUUID, chunks, parentUUID, encryptedChunk)
+			}); err != nil {
+				return nil, err
+			}
+			size += int64(n)
+			chunks++
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	lastModified := time.Now()
+	metadata := struct {
+		Name         string `json:"name"`
+		Size         int    `json:"size"`
+		MimeType     string `json:"mime"`
+		Key          string `json:"key"`
+		LastModified int    `json:"lastModified"`
+	}{
+		Name:         name,
+		Size:         int(size),
+		MimeType:     mimeType,
+		Key:          key,
+		LastModified: int(lastModified.UnixMilli()),
+	}
+	metadataStr, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	metadataEncrypted, err := crypto.EncryptMetadata(string(metadataStr), filen.CurrentMasterKey())
+	if err != nil {
+		return nil, err
+	}
+	nameHashed := hex.EncodeToString(crypto.RunSHA521([]byte(name)))
+
+	response, err := callWithPacer(filen, bind4(filen.client.UploadDone, fileUUID, metadataEncrypted, nameHashed, parentUUID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		UUID:          fileUUID,
+		Name:          name,
+		Size:          size,
+		MimeType:      mimeType,
+		EncryptionKey: []byte(key),
+		Created:       lastModified,
+		LastModified:  lastModified,
+		ParentUUID:    parentUUID,
+		Region:        response.Region,
+		Bucket:        response.Bucket,
+		Chunks:        chunks,
+	}, nil
+}
+
+// DownloadFileChunk fetches and decrypts a single chunk of file, by index.
+func (filen *Filen) DownloadFileChunk(file *File, index int) ([]byte, error) {
+	encryptedChunk, err := callWithPacer(filen, bind4(filen.client.DownloadChunk, file.UUID, file.Region, file.Bucket, index))
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptData(encryptedChunk, file.EncryptionKey)
+}
+
+// DownloadFile writes file's full, decrypted contents to w, one chunk at a
+// time.
+func (filen *Filen) DownloadFile(file *File, w io.Writer) error {
+	for i := 0; i < file.Chunks; i++ {
+		chunk, err := filen.DownloadFileChunk(file, i)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateFileKey returns a fresh random per-file encryption key, in the
+// same hex-string form ReadDirectory expects in a file's decrypted
+// metadata.Key.
+func generateFileKey() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}